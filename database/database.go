@@ -0,0 +1,57 @@
+// Package database provides SQL-backed persistence for configured services:
+// their own JSON config, plus auxiliary state that individual services
+// layer on top of it (per-room/per-user preferences, dedup bookkeeping, and
+// the like) rather than storing in the config itself.
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/matrix-org/go-neb/types"
+)
+
+// ServiceDB wraps the SQL database used to persist service state.
+type ServiceDB struct {
+	db *sql.DB
+}
+
+var (
+	mu        sync.RWMutex
+	singleton *ServiceDB
+)
+
+// SetServiceDB installs db as the ServiceDB returned by GetServiceDB.
+func SetServiceDB(db *ServiceDB) {
+	mu.Lock()
+	defer mu.Unlock()
+	singleton = db
+}
+
+// GetServiceDB returns the ServiceDB installed by SetServiceDB.
+func GetServiceDB() *ServiceDB {
+	mu.RLock()
+	defer mu.RUnlock()
+	return singleton
+}
+
+// UpdateService persists service's current config, overwriting whatever was
+// previously stored under its ServiceID.
+func (d *ServiceDB) UpdateService(service types.Service) error {
+	body, err := json.Marshal(service)
+	if err != nil {
+		return fmt.Errorf("could not marshal service: %w", err)
+	}
+
+	_, err = d.db.Exec(
+		`UPDATE services SET config = $1 WHERE service_id = $2`,
+		string(body), service.ServiceID(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not update service: %w", err)
+	}
+
+	return nil
+}
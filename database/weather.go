@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// weatherSchema creates the tables backing the weather service's per-user
+// and per-room preferences and its alert-dedup bookkeeping. It's applied
+// lazily, the first time any of the methods below runs, rather than wired
+// into a central migration so this one service doesn't need a schema
+// version bump of its own.
+const weatherSchema = `
+CREATE TABLE IF NOT EXISTS weather_user_home (
+	service_id TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	location   TEXT NOT NULL,
+	PRIMARY KEY (service_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS weather_room_settings (
+	service_id TEXT NOT NULL,
+	room_id    TEXT NOT NULL,
+	unit       TEXT NOT NULL DEFAULT '',
+	lang       TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (service_id, room_id)
+);
+
+CREATE TABLE IF NOT EXISTS weather_seen_alerts (
+	service_id TEXT NOT NULL,
+	alert_id   TEXT NOT NULL,
+	PRIMARY KEY (service_id, alert_id)
+);
+`
+
+var (
+	weatherSchemaOnce sync.Once
+	weatherSchemaErr  error
+)
+
+func (d *ServiceDB) ensureWeatherSchema() error {
+	weatherSchemaOnce.Do(func() {
+		_, weatherSchemaErr = d.db.Exec(weatherSchema)
+	})
+
+	return weatherSchemaErr
+}
+
+// GetWeatherUserHome returns userID's saved default location for serviceID,
+// or "" if none has been set.
+func (d *ServiceDB) GetWeatherUserHome(serviceID, userID string) (string, error) {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return "", err
+	}
+
+	var home string
+	err := d.db.QueryRow(
+		`SELECT location FROM weather_user_home WHERE service_id = $1 AND user_id = $2`,
+		serviceID, userID,
+	).Scan(&home)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not load weather home: %w", err)
+	}
+
+	return home, nil
+}
+
+// SetWeatherUserHome saves location as userID's default for serviceID.
+func (d *ServiceDB) SetWeatherUserHome(serviceID, userID, location string) error {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec(
+		`INSERT INTO weather_user_home (service_id, user_id, location) VALUES ($1, $2, $3)
+		 ON CONFLICT (service_id, user_id) DO UPDATE SET location = excluded.location`,
+		serviceID, userID, location,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save weather home: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWeatherUserHome clears userID's saved default location for
+// serviceID.
+func (d *ServiceDB) DeleteWeatherUserHome(serviceID, userID string) error {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec(
+		`DELETE FROM weather_user_home WHERE service_id = $1 AND user_id = $2`,
+		serviceID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not clear weather home: %w", err)
+	}
+
+	return nil
+}
+
+// GetWeatherRoomUnit returns roomID's unit override for serviceID, or "" if
+// none has been set.
+func (d *ServiceDB) GetWeatherRoomUnit(serviceID, roomID string) (string, error) {
+	return d.getWeatherRoomSetting(serviceID, roomID, "unit")
+}
+
+// SetWeatherRoomUnit sets roomID's unit override for serviceID.
+func (d *ServiceDB) SetWeatherRoomUnit(serviceID, roomID, unit string) error {
+	return d.setWeatherRoomSetting(serviceID, roomID, "unit", unit)
+}
+
+// GetWeatherRoomLang returns roomID's language override for serviceID, or
+// "" if none has been set.
+func (d *ServiceDB) GetWeatherRoomLang(serviceID, roomID string) (string, error) {
+	return d.getWeatherRoomSetting(serviceID, roomID, "lang")
+}
+
+// SetWeatherRoomLang sets roomID's language override for serviceID.
+func (d *ServiceDB) SetWeatherRoomLang(serviceID, roomID, lang string) error {
+	return d.setWeatherRoomSetting(serviceID, roomID, "lang", lang)
+}
+
+// getWeatherRoomSetting and setWeatherRoomSetting share the upsert-by-column
+// logic behind the unit/lang getters and setters above; column is always a
+// literal passed by this file, never user input, so building the query with
+// fmt.Sprintf is safe here.
+func (d *ServiceDB) getWeatherRoomSetting(serviceID, roomID, column string) (string, error) {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return "", err
+	}
+
+	var value string
+	err := d.db.QueryRow(
+		fmt.Sprintf(`SELECT %s FROM weather_room_settings WHERE service_id = $1 AND room_id = $2`, column),
+		serviceID, roomID,
+	).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not load weather room %s: %w", column, err)
+	}
+
+	return value, nil
+}
+
+func (d *ServiceDB) setWeatherRoomSetting(serviceID, roomID, column, value string) error {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec(
+		fmt.Sprintf(`INSERT INTO weather_room_settings (service_id, room_id, %[1]s) VALUES ($1, $2, $3)
+			ON CONFLICT (service_id, room_id) DO UPDATE SET %[1]s = excluded.%[1]s`, column),
+		serviceID, roomID, value,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save weather room %s: %w", column, err)
+	}
+
+	return nil
+}
+
+// IsWeatherAlertSeen reports whether alertID has already been notified for
+// serviceID.
+func (d *ServiceDB) IsWeatherAlertSeen(serviceID, alertID string) (bool, error) {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return false, err
+	}
+
+	var dummy int
+	err := d.db.QueryRow(
+		`SELECT 1 FROM weather_seen_alerts WHERE service_id = $1 AND alert_id = $2`,
+		serviceID, alertID,
+	).Scan(&dummy)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not check seen alert: %w", err)
+	}
+
+	return true, nil
+}
+
+// MarkWeatherAlertSeen records that alertID has been notified for
+// serviceID, so the alert poller doesn't re-send it on the next poll.
+func (d *ServiceDB) MarkWeatherAlertSeen(serviceID, alertID string) error {
+	if err := d.ensureWeatherSchema(); err != nil {
+		return err
+	}
+
+	_, err := d.db.Exec(
+		`INSERT INTO weather_seen_alerts (service_id, alert_id) VALUES ($1, $2)
+		 ON CONFLICT (service_id, alert_id) DO NOTHING`,
+		serviceID, alertID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not mark alert seen: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matrix-org/go-neb/database"
+	"github.com/matrix-org/go-neb/services/weather/provider"
+	"github.com/matrix-org/go-neb/types"
+	"github.com/matrix-org/gomatrix"
+	"github.com/sirupsen/logrus"
+)
+
+// alertPollInterval is how often subscribed rooms are checked for new
+// severe weather alerts.
+const alertPollInterval = 10 * time.Minute
+
+// Subscription binds a room to a location whose alerts it wants to hear
+// about, optionally filtered to a minimum severity.
+type Subscription struct {
+	RoomID      string `json:"room_id"`
+	Location    string `json:"location"`
+	MinSeverity string `json:"min_severity"`
+}
+
+// alertID derives a stable identifier for an alert so repeated polls of the
+// same still-active alert don't re-notify a room.
+func alertID(a provider.Alert) string {
+	return a.SenderName + "|" + a.Event + "|" + strconv.FormatInt(a.Start.Unix(), 10)
+}
+
+// severityRank orders CAP/NWS severity levels so meetsSeverity can compare
+// thresholds rather than match tags exactly.
+var severityRank = map[string]int{
+	"minor":    1,
+	"moderate": 2,
+	"severe":   3,
+	"extreme":  4,
+}
+
+// meetsSeverity reports whether a's severity is at least min. An unset min
+// matches everything; an alert whose severity isn't a recognised CAP level
+// only matches when min is also unset.
+func meetsSeverity(a provider.Alert, min string) bool {
+	if min == "" {
+		return true
+	}
+
+	minRank, ok := severityRank[strings.ToLower(min)]
+	if !ok {
+		return false
+	}
+
+	return severityRank[strings.ToLower(a.Severity)] >= minRank
+}
+
+// OnPoll checks every subscribed room for new alerts, posting any that
+// haven't been seen before and returning the time of the next poll.
+func (s *Service) OnPoll(cli *gomatrix.Client) time.Time {
+	s.subsMu.Lock()
+	subs := append([]Subscription(nil), s.Subscriptions...)
+	s.subsMu.Unlock()
+
+	for _, sub := range subs {
+		if err := s.pollSubscription(cli, sub); err != nil {
+			log.WithFields(logrus.Fields{
+				logrus.ErrorKey: err,
+				"room_id":       sub.RoomID,
+				"location":      sub.Location,
+			}).Error("weather: failed to poll alerts")
+		}
+	}
+
+	return time.Now().Add(alertPollInterval)
+}
+
+func (s *Service) pollSubscription(cli *gomatrix.Client, sub Subscription) error {
+	loc, err := s.resolveLocation(cli, strings.Split(sub.Location, " "))
+	if err != nil {
+		return err
+	}
+
+	unit, err := s.effectiveUnit(sub.RoomID)
+	if err != nil {
+		return err
+	}
+
+	lang, err := s.effectiveLang(sub.RoomID)
+	if err != nil {
+		return err
+	}
+
+	p, err := s.weatherProvider()
+	if err != nil {
+		return err
+	}
+
+	alerts, err := p.Alerts(context.Background(), loc, provider.Options{Units: unit, Lang: lang})
+	if err != nil {
+		return err
+	}
+
+	store := database.GetServiceDB()
+
+	for _, a := range alerts {
+		if !meetsSeverity(a, sub.MinSeverity) {
+			continue
+		}
+
+		id := alertID(a)
+
+		seen, err := store.IsWeatherAlertSeen(s.ServiceID(), id)
+		if err != nil {
+			return err
+		}
+		if seen {
+			continue
+		}
+
+		body := fmt.Sprintf("[%s] %s for %s: %s", a.SenderName, a.Event, sub.Location, a.Description)
+
+		if _, err := cli.SendMessageEvent(sub.RoomID, "m.room.message", gomatrix.HTMLMessage{
+			MsgType:       "m.notice",
+			Body:          body,
+			Format:        "org.matrix.custom.html",
+			FormattedBody: fmt.Sprintf("<strong>[%s] %s</strong> for %s: %s", a.SenderName, a.Event, sub.Location, a.Description),
+		}); err != nil {
+			return fmt.Errorf("could not send alert to %s: %w", sub.RoomID, err)
+		}
+
+		if err := store.MarkWeatherAlertSeen(s.ServiceID(), id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// alertCommands returns the !weather alerts subscribe|unsubscribe|list commands.
+func (s *Service) alertCommands(client *gomatrix.Client) []types.Command {
+	return []types.Command{
+		{
+			Path: []string{"weather", "alerts", "subscribe"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.subscribeAlerts(roomID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "alerts", "unsubscribe"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.unsubscribeAlerts(roomID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "alerts", "list"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.listAlerts(roomID)
+			},
+		},
+	}
+}
+
+func (s *Service) subscribeAlerts(roomID string, args []string) (interface{}, error) {
+	if len(args) < 1 {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "Usage: !weather alerts subscribe (city[,country])|(postal code[,country]) [min_severity]",
+		}, nil
+	}
+
+	minSeverity := ""
+	location := args
+	if len(args) > 1 {
+		if _, ok := severityRank[strings.ToLower(args[len(args)-1])]; ok {
+			minSeverity = args[len(args)-1]
+			location = args[:len(args)-1]
+		}
+	}
+
+	sub := Subscription{
+		RoomID:      roomID,
+		Location:    strings.Join(location, " "),
+		MinSeverity: minSeverity,
+	}
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	for i, existing := range s.Subscriptions {
+		if existing.RoomID == roomID && existing.Location == sub.Location {
+			if existing.MinSeverity == sub.MinSeverity {
+				return &gomatrix.TextMessage{
+					MsgType: "m.notice",
+					Body:    fmt.Sprintf("This room is already subscribed to alerts for %s", sub.Location),
+				}, nil
+			}
+
+			s.Subscriptions[i].MinSeverity = sub.MinSeverity
+			if err := database.GetServiceDB().UpdateService(s); err != nil {
+				return nil, fmt.Errorf("could not persist subscription: %w", err)
+			}
+
+			return &gomatrix.TextMessage{
+				MsgType: "m.notice",
+				Body:    fmt.Sprintf("Updated this room's alert subscription for %s", sub.Location),
+			}, nil
+		}
+	}
+
+	s.Subscriptions = append(s.Subscriptions, sub)
+	if err := database.GetServiceDB().UpdateService(s); err != nil {
+		return nil, fmt.Errorf("could not persist subscription: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("Subscribed this room to alerts for %s", sub.Location),
+	}, nil
+}
+
+func (s *Service) unsubscribeAlerts(roomID string, args []string) (interface{}, error) {
+	if len(args) < 1 {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "Usage: !weather alerts unsubscribe (city[,country])|(postal code[,country])",
+		}, nil
+	}
+
+	location := strings.Join(args, " ")
+
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	var kept []Subscription
+	removed := false
+	for _, existing := range s.Subscriptions {
+		if existing.RoomID == roomID && existing.Location == location {
+			removed = true
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	s.Subscriptions = kept
+
+	if !removed {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    fmt.Sprintf("This room isn't subscribed to alerts for %s", location),
+		}, nil
+	}
+
+	if err := database.GetServiceDB().UpdateService(s); err != nil {
+		return nil, fmt.Errorf("could not persist subscription: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("Unsubscribed this room from alerts for %s", location),
+	}, nil
+}
+
+func (s *Service) listAlerts(roomID string) (interface{}, error) {
+	s.subsMu.Lock()
+	subs := append([]Subscription(nil), s.Subscriptions...)
+	s.subsMu.Unlock()
+
+	var locations []string
+	for _, sub := range subs {
+		if sub.RoomID == roomID {
+			locations = append(locations, sub.Location)
+		}
+	}
+
+	if len(locations) == 0 {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "This room has no weather alert subscriptions",
+		}, nil
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    "Subscribed to alerts for: " + strings.Join(locations, ", "),
+	}, nil
+}
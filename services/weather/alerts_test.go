@@ -0,0 +1,56 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matrix-org/go-neb/services/weather/provider"
+)
+
+func TestMeetsSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		severity string
+		min      string
+		want     bool
+	}{
+		{"no minimum set", "minor", "", true},
+		{"equal severity meets minimum", "moderate", "moderate", true},
+		{"higher severity meets minimum", "extreme", "severe", true},
+		{"lower severity misses minimum", "moderate", "severe", false},
+		{"unrecognised alert severity misses any minimum", "", "minor", false},
+		{"minimum is case insensitive", "Severe", "SEVERE", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := provider.Alert{Severity: tt.severity}
+			if got := meetsSeverity(a, tt.min); got != tt.want {
+				t.Errorf("meetsSeverity(%q, %q) = %v, want %v", tt.severity, tt.min, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertID(t *testing.T) {
+	start := time.Date(2026, 7, 27, 12, 0, 0, 0, time.UTC)
+
+	a := provider.Alert{SenderName: "NWS", Event: "Flood Warning", Start: start}
+	id := alertID(a)
+
+	if id != alertID(a) {
+		t.Errorf("alertID is not stable across calls")
+	}
+
+	b := a
+	b.Description = "different description, same identity"
+	if alertID(b) != id {
+		t.Errorf("alertID changed for a field it shouldn't depend on")
+	}
+
+	c := a
+	c.Event = "Flood Watch"
+	if alertID(c) == id {
+		t.Errorf("alertID did not change for a different event")
+	}
+}
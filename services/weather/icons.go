@@ -0,0 +1,80 @@
+package weather
+
+import "fmt"
+
+// glyphForCode returns an emoji glyph for an OpenWeather-style condition
+// code. Ranges follow OpenWeather's own table:
+// https://openweathermap.org/weather-conditions
+func glyphForCode(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return "⛈️"
+	case code >= 300 && code < 400:
+		return "🌦️"
+	case code >= 500 && code < 600:
+		return "🌧️"
+	case code >= 600 && code < 700:
+		return "❄️"
+	case code >= 700 && code < 800:
+		return "🌫️"
+	case code == 800:
+		return "☀️"
+	case code > 800:
+		return "☁️"
+	default:
+		return "🌡️"
+	}
+}
+
+// asciiArt returns a compact 3-line wttr.in-style ASCII sketch for code.
+func asciiArt(code int) [3]string {
+	switch {
+	case code >= 200 && code < 300:
+		return [3]string{
+			`    .--.    `,
+			` .-(    ). ⚡`,
+			`(___.__)__)`,
+		}
+	case code >= 300 && code < 400, code >= 500 && code < 600:
+		return [3]string{
+			`    .--.    `,
+			` .-(    ).  `,
+			`  ' ' ' '   `,
+		}
+	case code >= 600 && code < 700:
+		return [3]string{
+			`    .--.    `,
+			` .-(    ).  `,
+			`  * *  * *  `,
+		}
+	case code >= 700 && code < 800:
+		return [3]string{
+			` _ - _ - _  `,
+			`_ - _ - _ - `,
+			` _ - _ - _  `,
+		}
+	case code == 800:
+		return [3]string{
+			`  \   /   `,
+			`   .-.    `,
+			`― (   ) ―`,
+		}
+	default:
+		return [3]string{
+			`    .--.    `,
+			` .-(    ).  `,
+			`(___.__)__) `,
+		}
+	}
+}
+
+// iconImgTag returns an <img> tag pointing at OpenWeather's icon CDN for
+// icon (e.g. "10d"), or "" if no icon code is available (only the
+// OpenWeather backend reports one).
+func iconImgTag(icon string) string {
+	if icon == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(`<img src="https://openweathermap.org/img/wn/%s@2x.png" width="32" height="32">`, icon)
+}
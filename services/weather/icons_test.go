@@ -0,0 +1,37 @@
+package weather
+
+import "testing"
+
+func TestGlyphForCode(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{210, "⛈️"},
+		{321, "🌦️"},
+		{501, "🌧️"},
+		{601, "❄️"},
+		{741, "🌫️"},
+		{800, "☀️"},
+		{803, "☁️"},
+		{-1, "🌡️"},
+	}
+
+	for _, tt := range tests {
+		if got := glyphForCode(tt.code); got != tt.want {
+			t.Errorf("glyphForCode(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestIconImgTag(t *testing.T) {
+	if got := iconImgTag(""); got != "" {
+		t.Errorf("iconImgTag(\"\") = %q, want empty string", got)
+	}
+
+	got := iconImgTag("10d")
+	want := `<img src="https://openweathermap.org/img/wn/10d@2x.png" width="32" height="32">`
+	if got != want {
+		t.Errorf("iconImgTag(\"10d\") = %q, want %q", got, want)
+	}
+}
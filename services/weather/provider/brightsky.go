@@ -0,0 +1,293 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// brightSkyName is registered as "meteologix" since Bright Sky is the free
+// API this bot uses to surface DWD (Deutscher Wetterdienst) data, which is
+// what Meteologix itself is ultimately sourced from for German locations.
+const brightSkyName = "meteologix"
+const brightSkyAPIBase = "https://api.brightsky.dev"
+
+// brightSky is the Provider backed by the Bright Sky wrapper around DWD's
+// open data. It needs no API key.
+type brightSky struct{}
+
+func init() {
+	Register(brightSkyName, func(apiKey string) Provider {
+		return &brightSky{}
+	})
+}
+
+type brightSkyWeather struct {
+	Timestamp         string  `json:"timestamp"`
+	Temperature       float64 `json:"temperature"`
+	WindSpeed         float64 `json:"wind_speed"`
+	WindDirection     float64 `json:"wind_direction"`
+	RelativeHumidity  float64 `json:"relative_humidity"`
+	Precipitation     float64 `json:"precipitation"`
+	Condition         string  `json:"condition"`
+	Icon              string  `json:"icon"`
+	SunshineMinutes10 float64 `json:"sunshine_10"`
+}
+
+type brightSkyCurrentResponse struct {
+	Weather brightSkyWeather `json:"weather"`
+}
+
+type brightSkyWeatherResponse struct {
+	Weather []brightSkyWeather `json:"weather"`
+}
+
+type brightSkyAlert struct {
+	HeadlineEN    string `json:"headline_en"`
+	EventEN       string `json:"event_en"`
+	DescriptionEN string `json:"description_en"`
+	Onset         string `json:"onset"`
+	Expires       string `json:"expires"`
+	Severity      string `json:"severity"`
+}
+
+type brightSkyAlertsResponse struct {
+	Alerts []brightSkyAlert `json:"alerts"`
+}
+
+func (p *brightSky) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	u, err := url.Parse(brightSkyAPIBase + path)
+	if err != nil {
+		return fmt.Errorf("could not parse base url: %w", err)
+	}
+
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("could not create http request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making weather request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("invalid response: %s", string(b))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid weather response: %w", err)
+	}
+
+	return nil
+}
+
+// openMeteoGeocodeAPIBase is Open-Meteo's free, keyless geocoding endpoint.
+// It's used instead of OpenWeather's geocoding so brightSky stays usable
+// with no api_key configured at all.
+const openMeteoGeocodeAPIBase = "https://geocoding-api.open-meteo.com/v1/search"
+
+type openMeteoGeocodeResult struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type openMeteoGeocodeResponse struct {
+	Results []openMeteoGeocodeResult `json:"results"`
+}
+
+// Geocode resolves name to coordinates via Open-Meteo's geocoding API,
+// which (like Bright Sky itself) needs no API key.
+func (p *brightSky) Geocode(ctx context.Context, name string) (Location, error) {
+	u, err := url.Parse(openMeteoGeocodeAPIBase)
+	if err != nil {
+		return Location{}, fmt.Errorf("could not parse geocode url: %w", err)
+	}
+
+	q := u.Query()
+	q.Add("name", name)
+	q.Add("count", "1")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Location{}, fmt.Errorf("could not create http request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Location{}, fmt.Errorf("error making geocode request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return Location{}, err
+		}
+
+		return Location{}, fmt.Errorf("invalid response: %s", string(b))
+	}
+
+	var result openMeteoGeocodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Location{}, fmt.Errorf("invalid geocode response: %w", err)
+	}
+
+	if len(result.Results) == 0 {
+		return Location{}, fmt.Errorf("no location found for %q", name)
+	}
+
+	r := result.Results[0]
+	return Location{Name: name, Lat: r.Latitude, Lon: r.Longitude}, nil
+}
+
+func latLonQuery(loc Location) url.Values {
+	q := url.Values{}
+	q.Add("lat", strconv.FormatFloat(loc.Lat, 'f', -1, 64))
+	q.Add("lon", strconv.FormatFloat(loc.Lon, 'f', -1, 64))
+
+	return q
+}
+
+func (p *brightSky) Current(ctx context.Context, loc Location, opts Options) (Conditions, error) {
+	var resp brightSkyCurrentResponse
+	if err := p.get(ctx, "/current_weather", latLonQuery(loc), &resp); err != nil {
+		return Conditions{}, err
+	}
+
+	updated, _ := time.Parse(time.RFC3339, resp.Weather.Timestamp)
+
+	return Conditions{
+		Description: resp.Weather.Condition,
+		Code:        codeFromDescription(resp.Weather.Condition),
+		Temp:        convertTemp(resp.Weather.Temperature, opts.Units),
+		FeelsLike:   convertTemp(resp.Weather.Temperature, opts.Units),
+		Humidity:    resp.Weather.RelativeHumidity,
+		WindSpeed:   convertSpeed(resp.Weather.WindSpeed, opts.Units),
+		WindDeg:     resp.Weather.WindDirection,
+		Updated:     updated,
+	}, nil
+}
+
+func (p *brightSky) Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyConditions, error) {
+	if days <= 0 {
+		days = 5
+	}
+
+	now := time.Now()
+	q := latLonQuery(loc)
+	q.Add("date", now.Format("2006-01-02"))
+	q.Add("last_date", now.AddDate(0, 0, days).Format("2006-01-02"))
+
+	var resp brightSkyWeatherResponse
+	if err := p.get(ctx, "/weather", q, &resp); err != nil {
+		return nil, err
+	}
+
+	byDay := map[string]*DailyConditions{}
+	var order []string
+	for _, h := range resp.Weather {
+		ts, err := time.Parse(time.RFC3339, h.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		key := ts.Format("2006-01-02")
+		d, ok := byDay[key]
+		if !ok {
+			d = &DailyConditions{
+				Date:        time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, ts.Location()),
+				Description: h.Condition,
+				Code:        codeFromDescription(h.Condition),
+				TempMin:     convertTemp(h.Temperature, opts.Units),
+				TempMax:     convertTemp(h.Temperature, opts.Units),
+			}
+			byDay[key] = d
+			order = append(order, key)
+		}
+
+		t := convertTemp(h.Temperature, opts.Units)
+		if t < d.TempMin {
+			d.TempMin = t
+		}
+		if t > d.TempMax {
+			d.TempMax = t
+		}
+		if h.Precipitation > 0 {
+			d.Pop = 1
+		}
+	}
+
+	out := make([]DailyConditions, 0, len(order))
+	for i, key := range order {
+		if i >= days {
+			break
+		}
+		out = append(out, *byDay[key])
+	}
+
+	return out, nil
+}
+
+func (p *brightSky) Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error) {
+	var resp brightSkyAlertsResponse
+	if err := p.get(ctx, "/alerts", latLonQuery(loc), &resp); err != nil {
+		return nil, err
+	}
+
+	out := make([]Alert, 0, len(resp.Alerts))
+	for _, a := range resp.Alerts {
+		onset, _ := time.Parse(time.RFC3339, a.Onset)
+		expires, _ := time.Parse(time.RFC3339, a.Expires)
+
+		out = append(out, Alert{
+			SenderName:  "DWD",
+			Event:       a.EventEN,
+			Start:       onset,
+			End:         expires,
+			Description: a.DescriptionEN,
+			Severity:    strings.ToLower(a.Severity),
+		})
+	}
+
+	return out, nil
+}
+
+// convertTemp converts a Bright Sky temperature, which is always reported in
+// Celsius, into the requested units.
+func convertTemp(celsius float64, units string) float64 {
+	switch units {
+	case "imperial":
+		return celsius*9/5 + 32
+	case "standard":
+		return celsius + 273.15
+	default:
+		return celsius
+	}
+}
+
+// convertSpeed converts a Bright Sky wind speed, which is always reported in
+// km/h, into the requested units (m/s for metric/standard, mph for imperial).
+func convertSpeed(kmh float64, units string) float64 {
+	if units == "imperial" {
+		return kmh * 0.621371
+	}
+
+	return kmh / 3.6
+}
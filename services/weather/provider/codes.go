@@ -0,0 +1,49 @@
+package provider
+
+import "strings"
+
+// codeFromDescription approximates an OpenWeather-style condition code (see
+// https://openweathermap.org/weather-conditions) from a free-text condition
+// description, for backends that don't report OpenWeather's own codes.
+func codeFromDescription(description string) int {
+	d := strings.ToLower(description)
+
+	switch {
+	case strings.Contains(d, "thunder"):
+		return 200
+	case strings.Contains(d, "drizzle"):
+		return 300
+	case strings.Contains(d, "snow"), strings.Contains(d, "sleet"), strings.Contains(d, "hail"):
+		return 600
+	case strings.Contains(d, "rain"), strings.Contains(d, "shower"):
+		return 500
+	case strings.Contains(d, "fog"), strings.Contains(d, "mist"), strings.Contains(d, "haze"), strings.Contains(d, "smoke"):
+		return 701
+	case strings.Contains(d, "overcast"), strings.Contains(d, "cloud"):
+		return 803
+	case strings.Contains(d, "clear"), strings.Contains(d, "sun"), strings.Contains(d, "dry"):
+		return 800
+	default:
+		return 800
+	}
+}
+
+// capSeverities are the recognised CAP/NWS severity levels, in increasing
+// order of severity.
+var capSeverities = map[string]bool{
+	"minor": true, "moderate": true, "severe": true, "extreme": true,
+}
+
+// severityFromTags picks out a recognised CAP severity level from a raw tag
+// list, for backends (like OpenWeather) whose `tags` field mixes event
+// categories and severity together rather than reporting severity
+// separately.
+func severityFromTags(tags []string) string {
+	for _, t := range tags {
+		if lower := strings.ToLower(t); capSeverities[lower] {
+			return lower
+		}
+	}
+
+	return ""
+}
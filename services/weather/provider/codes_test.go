@@ -0,0 +1,46 @@
+package provider
+
+import "testing"
+
+func TestCodeFromDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		want        int
+	}{
+		{"Thunderstorm", 200},
+		{"Light drizzle", 300},
+		{"Heavy snow", 600},
+		{"Moderate rain", 500},
+		{"Patchy fog", 701},
+		{"Overcast", 803},
+		{"Clear sky", 800},
+		{"Unrecognised condition", 800},
+	}
+
+	for _, tt := range tests {
+		if got := codeFromDescription(tt.description); got != tt.want {
+			t.Errorf("codeFromDescription(%q) = %d, want %d", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestSeverityFromTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{"no tags", nil, ""},
+		{"no recognised severity", []string{"Flood"}, ""},
+		{"recognised severity", []string{"Flood", "Severe"}, "severe"},
+		{"case insensitive", []string{"EXTREME"}, "extreme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := severityFromTags(tt.tags); got != tt.want {
+				t.Errorf("severityFromTags(%v) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const openWeatherName = "openweather"
+const oneCallAPIBase = "https://api.openweathermap.org/data/2.5/onecall"
+
+var httpClient = &http.Client{}
+
+// openWeather is the Provider backing the bot's original OpenWeather One
+// Call integration.
+type openWeather struct {
+	apiKey string
+}
+
+func init() {
+	Register(openWeatherName, func(apiKey string) Provider {
+		return &openWeather{apiKey: apiKey}
+	})
+}
+
+type owWeather struct {
+	ID          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+type owTime time.Time
+
+func (t *owTime) UnmarshalJSON(b []byte) error {
+	unix, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*t = owTime(time.Unix(unix, 0))
+
+	return nil
+}
+
+type owCurrent struct {
+	Dt        owTime      `json:"dt"`
+	Temp      float64     `json:"temp"`
+	FeelsLike float64     `json:"feels_like"`
+	Humidity  float64     `json:"humidity"`
+	WindSpeed float64     `json:"wind_speed"`
+	WindDeg   float64     `json:"wind_deg"`
+	Weather   []owWeather `json:"weather"`
+}
+
+type owDailyTemp struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type owDaily struct {
+	Dt      owTime      `json:"dt"`
+	Sunrise owTime      `json:"sunrise"`
+	Sunset  owTime      `json:"sunset"`
+	Temp    owDailyTemp `json:"temp"`
+	Weather []owWeather `json:"weather"`
+	Pop     float64     `json:"pop"`
+}
+
+type owHourly struct {
+	Dt        owTime      `json:"dt"`
+	Temp      float64     `json:"temp"`
+	FeelsLike float64     `json:"feels_like"`
+	Weather   []owWeather `json:"weather"`
+	Pop       float64     `json:"pop"`
+}
+
+type owAlert struct {
+	SenderName  string   `json:"sender_name"`
+	Event       string   `json:"event"`
+	Start       owTime   `json:"start"`
+	End         owTime   `json:"end"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+type oneCallResponse struct {
+	Current owCurrent  `json:"current"`
+	Hourly  []owHourly `json:"hourly"`
+	Daily   []owDaily  `json:"daily"`
+	Alerts  []owAlert  `json:"alerts"`
+}
+
+func simpleString(ws []owWeather) string {
+	if len(ws) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (%s)", ws[0].Main, ws[0].Description)
+}
+
+func conditionCode(ws []owWeather) int {
+	if len(ws) == 0 {
+		return 800
+	}
+
+	return ws[0].ID
+}
+
+func conditionIcon(ws []owWeather) string {
+	if len(ws) == 0 {
+		return ""
+	}
+
+	return ws[0].Icon
+}
+
+func (p *openWeather) fetch(ctx context.Context, loc Location, exclude string, opts Options) (*oneCallResponse, error) {
+	u, err := url.Parse(oneCallAPIBase)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse base url: %w", err)
+	}
+
+	q := u.Query()
+	q.Add("lat", strconv.FormatFloat(loc.Lat, 'f', -1, 64))
+	q.Add("lon", strconv.FormatFloat(loc.Lon, 'f', -1, 64))
+	if exclude != "" {
+		q.Add("exclude", exclude)
+	}
+	if opts.Units != "" {
+		q.Add("units", opts.Units)
+	}
+	if opts.Lang != "" {
+		q.Add("lang", opts.Lang)
+	}
+	q.Add("appid", p.apiKey)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create http request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making weather request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("invalid response: %s", string(b))
+	}
+
+	var body oneCallResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid weather response: %w", err)
+	}
+
+	return &body, nil
+}
+
+func (p *openWeather) Current(ctx context.Context, loc Location, opts Options) (Conditions, error) {
+	body, err := p.fetch(ctx, loc, "minutely,hourly,daily,alerts", opts)
+	if err != nil {
+		return Conditions{}, err
+	}
+
+	return Conditions{
+		Description: simpleString(body.Current.Weather),
+		Code:        conditionCode(body.Current.Weather),
+		Icon:        conditionIcon(body.Current.Weather),
+		Temp:        body.Current.Temp,
+		FeelsLike:   body.Current.FeelsLike,
+		Humidity:    body.Current.Humidity,
+		WindSpeed:   body.Current.WindSpeed,
+		WindDeg:     body.Current.WindDeg,
+		Updated:     time.Time(body.Current.Dt),
+	}, nil
+}
+
+func (p *openWeather) Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyConditions, error) {
+	body, err := p.fetch(ctx, loc, "current,minutely,hourly,alerts", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	daily := body.Daily
+	if days > 0 && len(daily) > days {
+		daily = daily[:days]
+	}
+
+	out := make([]DailyConditions, 0, len(daily))
+	for _, d := range daily {
+		out = append(out, DailyConditions{
+			Date:        time.Time(d.Dt),
+			Description: simpleString(d.Weather),
+			Code:        conditionCode(d.Weather),
+			TempMin:     d.Temp.Min,
+			TempMax:     d.Temp.Max,
+			Pop:         d.Pop,
+			Sunrise:     time.Time(d.Sunrise),
+			Sunset:      time.Time(d.Sunset),
+		})
+	}
+
+	return out, nil
+}
+
+func (p *openWeather) Hourly(ctx context.Context, loc Location, hours int, opts Options) ([]HourlyConditions, error) {
+	body, err := p.fetch(ctx, loc, "current,minutely,daily,alerts", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hourly := body.Hourly
+	if hours > 0 && len(hourly) > hours {
+		hourly = hourly[:hours]
+	}
+
+	out := make([]HourlyConditions, 0, len(hourly))
+	for _, h := range hourly {
+		out = append(out, HourlyConditions{
+			Time:        time.Time(h.Dt),
+			Description: simpleString(h.Weather),
+			Temp:        h.Temp,
+			FeelsLike:   h.FeelsLike,
+			Pop:         h.Pop,
+		})
+	}
+
+	return out, nil
+}
+
+func (p *openWeather) Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error) {
+	body, err := p.fetch(ctx, loc, "current,minutely,hourly,daily", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Alert, 0, len(body.Alerts))
+	for _, a := range body.Alerts {
+		out = append(out, Alert{
+			SenderName:  a.SenderName,
+			Event:       a.Event,
+			Start:       time.Time(a.Start),
+			End:         time.Time(a.End),
+			Description: a.Description,
+			Severity:    severityFromTags(a.Tags),
+			Tags:        a.Tags,
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,134 @@
+// Package provider defines the backend abstraction services/weather uses to
+// fetch weather data, along with a registry of named implementations.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Location identifies where a weather lookup is for. Name is the raw,
+// human-entered location string; Lat/Lon are populated when the caller has
+// already geocoded it. A Provider that doesn't need coordinates (e.g. one
+// that accepts place names directly) may ignore Lat/Lon.
+type Location struct {
+	Name string
+	Lat  float64
+	Lon  float64
+}
+
+// Options carries the per-request settings a Provider should honour.
+type Options struct {
+	// Units is one of "standard", "metric" or "imperial".
+	Units string
+	// Lang is an OpenWeather-style language code, e.g. "en", "de". May be
+	// empty, in which case the Provider should fall back to its own default.
+	Lang string
+}
+
+// Conditions is a snapshot of current weather at a Location.
+//
+// Code is an OpenWeather-style condition code (see
+// https://openweathermap.org/weather-conditions) that callers can use to
+// pick a glyph/icon to display; backends that don't natively report one
+// approximate it from their own condition description. Icon is OpenWeather's
+// own icon code (e.g. "10d") and is only populated by the OpenWeather
+// backend, since it's used to link directly to OpenWeather's icon images.
+type Conditions struct {
+	Description string
+	Code        int
+	Icon        string
+	Temp        float64
+	FeelsLike   float64
+	Humidity    float64
+	WindSpeed   float64
+	WindDeg     float64
+	Updated     time.Time
+}
+
+// DailyConditions summarises a single day of a forecast.
+type DailyConditions struct {
+	Date        time.Time
+	Description string
+	Code        int
+	TempMin     float64
+	TempMax     float64
+	Pop         float64
+	Sunrise     time.Time
+	Sunset      time.Time
+}
+
+// Alert is a severe weather alert covering a Location.
+//
+// Severity is a normalised CAP/NWS severity level ("minor", "moderate",
+// "severe" or "extreme") when the backend can determine one, else "". Tags
+// carries whatever other raw categories the backend reports (event type,
+// etc.) and shouldn't be treated as a severity ordering itself.
+type Alert struct {
+	SenderName  string
+	Event       string
+	Start       time.Time
+	End         time.Time
+	Description string
+	Severity    string
+	Tags        []string
+}
+
+// HourlyConditions summarises a single hour of a sub-daily forecast.
+type HourlyConditions struct {
+	Time        time.Time
+	Description string
+	Temp        float64
+	FeelsLike   float64
+	Pop         float64
+}
+
+// HourlyProvider is an optional capability a Provider may implement to
+// support sub-daily forecasts. Callers type-assert for it; the weather
+// service's hourly command reports hourly forecasts as unsupported when it's
+// absent.
+type HourlyProvider interface {
+	Hourly(ctx context.Context, loc Location, hours int, opts Options) ([]HourlyConditions, error)
+}
+
+// Geocoder is an optional capability a coordinate-based Provider may
+// implement when it can resolve a place name to a Location without relying
+// on OpenWeather's geocoding endpoint, which requires an OpenWeather API
+// key the Provider itself may not need. Callers should type-assert for it
+// before falling back to OpenWeather geocoding.
+type Geocoder interface {
+	Geocode(ctx context.Context, name string) (Location, error)
+}
+
+// Provider is the interface every weather backend implements.
+type Provider interface {
+	// Current returns the current conditions at loc.
+	Current(ctx context.Context, loc Location, opts Options) (Conditions, error)
+	// Forecast returns up to days days of daily forecast conditions at loc.
+	Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyConditions, error)
+	// Alerts returns any active severe weather alerts covering loc.
+	Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error)
+}
+
+// Factory constructs a Provider, given the API key configured on the
+// service (some providers, like wttr.in, ignore it entirely).
+type Factory func(apiKey string) Provider
+
+var factories = make(map[string]Factory)
+
+// Register adds a named Provider backend, mirroring types.RegisterService.
+// It should be called from the backend implementation's init().
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// New constructs the named Provider with the given API key.
+func New(name, apiKey string) (Provider, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown weather provider %q", name)
+	}
+
+	return factory(apiKey), nil
+}
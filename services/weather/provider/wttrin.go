@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// wttrInName is registered as "wttrin". wttr.in needs no API key, so this
+// backend works for services that haven't configured one; it is used only
+// when the service's provider is set to "wttrin".
+const wttrInName = "wttrin"
+const wttrInAPIBase = "https://wttr.in"
+
+// wttrIn is the Provider backed by wttr.in's `?format=j1` JSON endpoint.
+type wttrIn struct{}
+
+func init() {
+	Register(wttrInName, func(apiKey string) Provider {
+		return &wttrIn{}
+	})
+}
+
+type wttrInDesc struct {
+	Value string `json:"value"`
+}
+
+type wttrInCurrentCondition struct {
+	TempC       string       `json:"temp_C"`
+	TempF       string       `json:"temp_F"`
+	FeelsLikeC  string       `json:"FeelsLikeC"`
+	FeelsLikeF  string       `json:"FeelsLikeF"`
+	Humidity    string       `json:"humidity"`
+	WindSpeed   string       `json:"windspeedKmph"`
+	WindDeg     string       `json:"winddirDegree"`
+	WeatherDesc []wttrInDesc `json:"weatherDesc"`
+}
+
+type wttrInAstronomy struct {
+	Sunrise string `json:"sunrise"`
+	Sunset  string `json:"sunset"`
+}
+
+type wttrInDay struct {
+	Date      string            `json:"date"`
+	MaxTempC  string            `json:"maxtempC"`
+	MaxTempF  string            `json:"maxtempF"`
+	MinTempC  string            `json:"mintempC"`
+	MinTempF  string            `json:"mintempF"`
+	Astronomy []wttrInAstronomy `json:"astronomy"`
+	Hourly    []wttrInHour      `json:"hourly"`
+}
+
+type wttrInHour struct {
+	WeatherDesc  []wttrInDesc `json:"weatherDesc"`
+	ChanceOfRain string       `json:"chanceofrain"`
+}
+
+type wttrInResponse struct {
+	CurrentCondition []wttrInCurrentCondition `json:"current_condition"`
+	Weather          []wttrInDay              `json:"weather"`
+}
+
+func (p *wttrIn) fetch(ctx context.Context, loc Location) (*wttrInResponse, error) {
+	name := loc.Name
+	if name == "" {
+		name = fmt.Sprintf("%f,%f", loc.Lat, loc.Lon)
+	}
+
+	u, err := url.Parse(wttrInAPIBase + "/" + url.PathEscape(name))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse base url: %w", err)
+	}
+
+	q := u.Query()
+	q.Add("format", "j1")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create http request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making weather request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("invalid response: %s", string(b))
+	}
+
+	var body wttrInResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid weather response: %w", err)
+	}
+
+	return &body, nil
+}
+
+func wttrInDescString(ds []wttrInDesc) string {
+	if len(ds) == 0 {
+		return ""
+	}
+
+	return ds[0].Value
+}
+
+func parseWttrInTemp(celsiusStr, fahrenheitStr, units string) float64 {
+	switch units {
+	case "imperial":
+		f, _ := strconv.ParseFloat(fahrenheitStr, 64)
+		return f
+	case "standard":
+		c, _ := strconv.ParseFloat(celsiusStr, 64)
+		return c + 273.15
+	default:
+		c, _ := strconv.ParseFloat(celsiusStr, 64)
+		return c
+	}
+}
+
+func (p *wttrIn) Current(ctx context.Context, loc Location, opts Options) (Conditions, error) {
+	body, err := p.fetch(ctx, loc)
+	if err != nil {
+		return Conditions{}, err
+	}
+
+	if len(body.CurrentCondition) == 0 {
+		return Conditions{}, fmt.Errorf("wttr.in returned no current conditions for %q", loc.Name)
+	}
+
+	cur := body.CurrentCondition[0]
+	humidity, _ := strconv.ParseFloat(cur.Humidity, 64)
+	windKmph, _ := strconv.ParseFloat(cur.WindSpeed, 64)
+	windDeg, _ := strconv.ParseFloat(cur.WindDeg, 64)
+
+	description := wttrInDescString(cur.WeatherDesc)
+
+	return Conditions{
+		Description: description,
+		Code:        codeFromDescription(description),
+		Temp:        parseWttrInTemp(cur.TempC, cur.TempF, opts.Units),
+		FeelsLike:   parseWttrInTemp(cur.FeelsLikeC, cur.FeelsLikeF, opts.Units),
+		Humidity:    humidity,
+		WindSpeed:   convertSpeed(windKmph, opts.Units),
+		WindDeg:     windDeg,
+		Updated:     time.Now(),
+	}, nil
+}
+
+func (p *wttrIn) Forecast(ctx context.Context, loc Location, days int, opts Options) ([]DailyConditions, error) {
+	body, err := p.fetch(ctx, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	daily := body.Weather
+	if days > 0 && len(daily) > days {
+		daily = daily[:days]
+	}
+
+	out := make([]DailyConditions, 0, len(daily))
+	for _, d := range daily {
+		date, _ := time.Parse("2006-01-02", d.Date)
+
+		var description string
+		var pop float64
+		if len(d.Hourly) > 0 {
+			description = wttrInDescString(d.Hourly[len(d.Hourly)/2].WeatherDesc)
+			if rain, err := strconv.ParseFloat(d.Hourly[len(d.Hourly)/2].ChanceOfRain, 64); err == nil {
+				pop = rain / 100
+			}
+		}
+
+		var sunrise, sunset time.Time
+		if len(d.Astronomy) > 0 {
+			sunrise, _ = time.Parse("2006-01-02 03:04 PM", d.Date+" "+d.Astronomy[0].Sunrise)
+			sunset, _ = time.Parse("2006-01-02 03:04 PM", d.Date+" "+d.Astronomy[0].Sunset)
+		}
+
+		out = append(out, DailyConditions{
+			Date:        date,
+			Description: description,
+			Code:        codeFromDescription(description),
+			TempMin:     parseWttrInTemp(d.MinTempC, d.MinTempF, opts.Units),
+			TempMax:     parseWttrInTemp(d.MaxTempC, d.MaxTempF, opts.Units),
+			Pop:         pop,
+			Sunrise:     sunrise,
+			Sunset:      sunset,
+		})
+	}
+
+	return out, nil
+}
+
+// Alerts always returns no alerts: wttr.in doesn't expose any.
+func (p *wttrIn) Alerts(ctx context.Context, loc Location, opts Options) ([]Alert, error) {
+	return nil, nil
+}
@@ -0,0 +1,152 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/matrix-org/go-neb/database"
+	"github.com/matrix-org/go-neb/types"
+	"github.com/matrix-org/gomatrix"
+)
+
+// validUnits are the values OpenWeather accepts for its `units` parameter.
+var validUnits = map[string]bool{
+	"standard": true,
+	"metric":   true,
+	"imperial": true,
+}
+
+// effectiveUnit returns the units to request from OpenWeather for roomID:
+// the room's own override if one has been set, else the service's configured
+// default, else "imperial" (the historical default display of this bot).
+func (s *Service) effectiveUnit(roomID string) (string, error) {
+	unit, err := database.GetServiceDB().GetWeatherRoomUnit(s.ServiceID(), roomID)
+	if err != nil {
+		return "", fmt.Errorf("could not load room unit override: %w", err)
+	}
+	if unit != "" {
+		return unit, nil
+	}
+
+	if s.Unit != "" {
+		return s.Unit, nil
+	}
+
+	return "imperial", nil
+}
+
+// effectiveLang returns the `lang` to request from OpenWeather for roomID:
+// the room's own override if one has been set, else the service's configured
+// default, else "" (OpenWeather's own default of English).
+func (s *Service) effectiveLang(roomID string) (string, error) {
+	lang, err := database.GetServiceDB().GetWeatherRoomLang(s.ServiceID(), roomID)
+	if err != nil {
+		return "", fmt.Errorf("could not load room language override: %w", err)
+	}
+	if lang != "" {
+		return lang, nil
+	}
+
+	return s.Language, nil
+}
+
+// settingsCommands returns the !weather set units|lang commands.
+func (s *Service) settingsCommands(client *gomatrix.Client) []types.Command {
+	return []types.Command{
+		{
+			Path: []string{"weather", "set", "units"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.setUnits(roomID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "set", "lang"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.setLang(roomID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "set", "home"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.setHome(userID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "unset", "home"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.unsetHome(userID)
+			},
+		},
+	}
+}
+
+func (s *Service) setUnits(roomID string, args []string) (interface{}, error) {
+	if len(args) != 1 || !validUnits[strings.ToLower(args[0])] {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "Usage: !weather set units (metric|imperial|standard)",
+		}, nil
+	}
+
+	unit := strings.ToLower(args[0])
+	if err := database.GetServiceDB().SetWeatherRoomUnit(s.ServiceID(), roomID, unit); err != nil {
+		return nil, fmt.Errorf("could not store unit preference: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("This room will now see weather in %s units", unit),
+	}, nil
+}
+
+func (s *Service) setLang(roomID string, args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "Usage: !weather set lang <language code>",
+		}, nil
+	}
+
+	lang := strings.ToLower(args[0])
+	if err := database.GetServiceDB().SetWeatherRoomLang(s.ServiceID(), roomID, lang); err != nil {
+		return nil, fmt.Errorf("could not store language preference: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("This room will now see weather descriptions in %q", lang),
+	}, nil
+}
+
+// setHome saves location as userID's default, used by "!weather"/"!w" when
+// invoked with no arguments.
+func (s *Service) setHome(userID string, args []string) (interface{}, error) {
+	if len(args) < 1 {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    "Usage: !weather set home (city[,country])|(postal code[,country])",
+		}, nil
+	}
+
+	home := strings.Join(args, " ")
+	if err := database.GetServiceDB().SetWeatherUserHome(s.ServiceID(), userID, home); err != nil {
+		return nil, fmt.Errorf("could not store home location: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    fmt.Sprintf("Your default location is now %q. Just type !weather or !w to use it.", home),
+	}, nil
+}
+
+// unsetHome clears userID's default location.
+func (s *Service) unsetHome(userID string) (interface{}, error) {
+	if err := database.GetServiceDB().DeleteWeatherUserHome(s.ServiceID(), userID); err != nil {
+		return nil, fmt.Errorf("could not clear home location: %w", err)
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    "Your default location has been cleared",
+	}, nil
+}
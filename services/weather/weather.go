@@ -2,187 +2,128 @@
 package weather
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/dustin/go-humanize"
+	"github.com/matrix-org/go-neb/database"
+	"github.com/matrix-org/go-neb/services/weather/provider"
 	"github.com/matrix-org/go-neb/types"
 	"github.com/matrix-org/gomatrix"
+	"github.com/sirupsen/logrus"
 )
 
+var log = logrus.WithField("service", ServiceType)
+
 // ServiceType of the Weather service
 const ServiceType = "weather"
 
-const apiBase = "https://api.openweathermap.org/data/2.5/weather"
-
-var httpClient = &http.Client{}
-
-type weatherResponse struct {
-	ID         int64       `json:"id"`
-	Name       string      `json:"name"`
-	Coord      coord       `json:"coord"`
-	Weather    []weather   `json:"weather"`
-	Base       string      `json:"base"`
-	Main       mainInfo    `json:"main"`
-	Visibility float64     `json:"visibility"`
-	Wind       wind        `json:"wind"`
-	Rain       rain        `json:"rain"`
-	Snow       snow        `json:"snow"`
-	Dt         weatherTime `json:"dt"`
-	Sys        sys         `json:"sys"`
-	Timezone   int         `json:"timezone"`
-}
-
-func (w *weatherResponse) Conditions() weather {
-	if len(w.Weather) > 0 {
-		return w.Weather[0]
-	}
-
-	return weather{}
-}
-
-type weatherTime time.Time
-
-func (w *weatherTime) UnmarshalJSON(b []byte) error {
-	t, err := strconv.ParseInt(string(b), 10, 64)
-	if err != nil {
-		return err
-	}
-
-	*w = weatherTime(time.Unix(t, 0))
-
-	return nil
-}
-
-type sys struct {
-	Country string `json:"country"`
-	Sunrise int64  `json:"sunrise"`
-	Sunset  int64  `json:"sunset"`
-}
-
-type snow struct {
-	Hour1 float64 `json:"1h"`
-	Hour3 float64 `json:"3h"`
-}
+// defaultProviderName is used when the service config doesn't set Provider.
+const defaultProviderName = "openweather"
 
-type rain struct {
-	Hour1 float64 `json:"1h"`
-	Hour3 float64 `json:"3h"`
-}
+const geocodeAPIBase = "https://api.openweathermap.org/geo/1.0/direct"
 
-type clouds struct {
-	All float64 `json:"all"`
-}
+// numDailyForecastDays is how many days of a daily forecast are rendered by
+// `!weather forecast`.
+const numDailyForecastDays = 5
 
-type mainInfo struct {
-	Temp        temp    `json:"temp"`
-	FeelsLike   temp    `json:"feels_like"`
-	TempMin     temp    `json:"temp_min"`
-	TempMax     temp    `json:"temp_max"`
-	Pressure    float64 `json:"pressure"`
-	Humidity    float64 `json:"humidity"`
-	SeaLevel    float64 `json:"sea_level"`
-	GroundLevel float64 `json:"grnd_level"`
-}
+// numHourlyForecastHours is how many entries of an hourly forecast are
+// rendered by `!weather hourly`.
+const numHourlyForecastHours = 12
 
-func (m mainInfo) MinMax() string {
-	return fmt.Sprintf("%.2f°F / %.2f°F (%.2f°C / %.2f°C)", m.TempMax.f(), m.TempMin.f(), m.TempMax.c(), m.TempMin.c())
-}
-
-type weather struct {
-	ID          int    `json:"id"`
-	Main        string `json:"main"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
-}
-
-func (w weather) SimpleString() string {
-	return fmt.Sprintf("%s (%s)", w.Main, w.Description)
-}
+var httpClient = &http.Client{}
 
 type coord struct {
 	Lat float64 `json:"lat"`
 	Lon float64 `json:"lon"`
 }
 
-type temp float64
-
-func (t temp) f() temp {
-	return (t.c()*9/5 + 32)
-}
-
-func (t temp) c() temp {
-	return (t - 273.15)
+// geocodeCache caches location -> coord lookups for the lifetime of the
+// process so repeated searches for the same (city, country) don't need a
+// second geocoding round trip.
+var geocodeCache = struct {
+	sync.RWMutex
+	m map[string]coord
+}{m: make(map[string]coord)}
+
+type geoResult struct {
+	Name    string  `json:"name"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	Country string  `json:"country"`
+	State   string  `json:"state"`
 }
 
-func (t temp) String() string {
-	return fmt.Sprintf("%.2f°F (%.2f°C)", t.f(), t.c())
+// unitSuffix returns the display suffix for a temperature or wind speed
+// under the given `units` value ("metric", "imperial", or "" / "standard").
+func unitSuffix(unit string) (tempSuffix, speedSuffix string) {
+	switch unit {
+	case "imperial":
+		return "°F", "mph"
+	case "metric":
+		return "°C", "m/s"
+	default:
+		return "K", "m/s"
+	}
 }
 
-type speed float64
-
-func (s speed) MPH() speed {
-	return s * 0.621371
+func formatTemp(t float64, unit string) string {
+	tempSuffix, _ := unitSuffix(unit)
+	return fmt.Sprintf("%.2f%s", t, tempSuffix)
 }
 
-func (s speed) KMH() speed {
-	return s
+func formatWind(speed, deg float64, unit string) string {
+	_, speedSuffix := unitSuffix(unit)
+	return fmt.Sprintf("%s at %.1f %s", windDirection(deg), speed, speedSuffix)
 }
 
-type deg float64
-
-func (d deg) String() string {
+func windDirection(deg float64) string {
 	switch {
-	case d > 348.75 && d <= 360.0 || d > 0 && d <= 11.25:
+	case deg > 348.75 && deg <= 360.0 || deg > 0 && deg <= 11.25:
 		return "N"
-	case d > 11.25 && d <= 33.75:
+	case deg > 11.25 && deg <= 33.75:
 		return "NNE"
-	case d > 33.75 && d <= 56.25:
+	case deg > 33.75 && deg <= 56.25:
 		return "NE"
-	case d > 56.25 && d <= 78.75:
+	case deg > 56.25 && deg <= 78.75:
 		return "ENE"
-	case d > 78.75 && d <= 101.25:
+	case deg > 78.75 && deg <= 101.25:
 		return "E"
-	case d > 101.25 && d <= 123.75:
+	case deg > 101.25 && deg <= 123.75:
 		return "ESE"
-	case d > 123.75 && d <= 146.25:
+	case deg > 123.75 && deg <= 146.25:
 		return "SE"
-	case d > 146.25 && d <= 168.75:
+	case deg > 146.25 && deg <= 168.75:
 		return "SSE"
-	case d > 168.75 && d <= 191.25:
+	case deg > 168.75 && deg <= 191.25:
 		return "S"
-	case d > 191.25 && d <= 213.75:
+	case deg > 191.25 && deg <= 213.75:
 		return "SSW"
-	case d > 213.75 && d <= 236.25:
+	case deg > 213.75 && deg <= 236.25:
 		return "SW"
-	case d > 236.25 && d < 258.75:
+	case deg > 236.25 && deg < 258.75:
 		return "WSW"
-	case d > 258.75 && d <= 281.25:
+	case deg > 258.75 && deg <= 281.25:
 		return "W"
-	case d > 281.25 && d <= 303.75:
+	case deg > 281.25 && deg <= 303.75:
 		return "WNW"
-	case d > 303.75 && d <= 326.25:
+	case deg > 303.75 && deg <= 326.25:
 		return "NW"
-	case d > 326.25 && d <= 348.75:
+	case deg > 326.25 && deg <= 348.75:
 		return "NNW"
 	default:
 		return ""
 	}
 }
 
-type wind struct {
-	Speed speed `json:"speed"`
-	Deg   deg   `json:"deg"`
-}
-
-func (w wind) String() string {
-	return fmt.Sprintf("%s at %.1f MPH (%.1f km/h)", w.Deg, w.Speed.MPH(), w.Speed.KMH())
+func dailyMinMax(d provider.DailyConditions, unit string) string {
+	return fmt.Sprintf("%s / %s", formatTemp(d.TempMax, unit), formatTemp(d.TempMin, unit))
 }
 
 // Service contains the Config fields for the Weather service.
@@ -193,22 +134,56 @@ func (w wind) String() string {
 //   }
 type Service struct {
 	types.DefaultService
-	APIKey         string `json:"api_key"`
-	DefaultCountry string `json:"default_country"`
-	Unit           string `json:"unit"`
+	APIKey         string         `json:"api_key"`
+	DefaultCountry string         `json:"default_country"`
+	Unit           string         `json:"unit"`
+	Language       string         `json:"language"`
+	Provider       string         `json:"provider"`
+	Subscriptions  []Subscription `json:"subscriptions"`
+
+	// subsMu guards Subscriptions, which OnPoll reads on every poll while
+	// the alerts subscribe/unsubscribe commands can mutate it concurrently.
+	subsMu sync.Mutex
+}
+
+// providerName returns the configured provider backend, defaulting to
+// OpenWeather when unset.
+func (s *Service) providerName() string {
+	if s.Provider == "" {
+		return defaultProviderName
+	}
+
+	return s.Provider
+}
+
+// weatherProvider constructs the configured Provider backend.
+func (s *Service) weatherProvider() (provider.Provider, error) {
+	return provider.New(s.providerName(), s.APIKey)
 }
 
 // Commands supported:
-//    !imgur some_search_query_without_quotes
-// Responds with a suitable image into the same room as the command.
+//    !weather some_search_query_without_quotes
+// Responds with the current conditions for the given location.
 func (s *Service) Commands(client *gomatrix.Client) []types.Command {
-	return []types.Command{
+	cmds := []types.Command{
 		{
 			Path: []string{"weather", "help"},
 			Command: func(roomID, userID string, args []string) (interface{}, error) {
 				return usageMessage(), nil
 			},
 		},
+		{
+			Path: []string{"weather", "forecast"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.forecast(client, roomID, userID, args)
+			},
+		},
+		{
+			Path: []string{"weather", "hourly"},
+			Command: func(roomID, userID string, args []string) (interface{}, error) {
+				return s.hourly(client, roomID, userID, args)
+			},
+		},
 		{
 			Path: []string{"weather"},
 			Command: func(roomID, userID string, args []string) (interface{}, error) {
@@ -222,49 +197,150 @@ func (s *Service) Commands(client *gomatrix.Client) []types.Command {
 			},
 		},
 	}
+
+	cmds = append(cmds, s.alertCommands(client)...)
+	return append(cmds, s.settingsCommands(client)...)
 }
 
 // usageMessage returns a matrix TextMessage representation of the service usage
 func usageMessage() *gomatrix.TextMessage {
 	return &gomatrix.TextMessage{
 		MsgType: "m.notice",
-		Body:    `Usage: !weather (city[,country])|(postal code[,country])`,
+		Body: `Usage: !weather (city[,country])|(postal code[,country])
+       !weather forecast (city[,country])|(postal code[,country])
+       !weather hourly (city[,country])|(postal code[,country])
+       !weather set units (metric|imperial|standard)
+       !weather set lang <language code>
+       !weather set home (city[,country])|(postal code[,country])
+       !weather unset home`,
 	}
 }
 
-func (s *Service) search(client *gomatrix.Client, roomID, userID string, args []string) (interface{}, error) {
-	if len(args) < 1 {
-		return usageMessage(), nil
+// locationKey normalises a raw location string for use as a geocode cache key.
+func locationKey(country, argStr string) string {
+	key := strings.ToLower(strings.TrimSpace(argStr))
+	if !strings.Contains(key, ",") {
+		key += ", " + strings.ToLower(country)
 	}
 
-	u, err := url.Parse(apiBase)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse base url: %w", err)
-	}
+	return key
+}
 
+// geocode turns the raw command args into a "city, country" query string and
+// geocodes it via OpenWeather to a lat/lon pair, using the in-memory cache
+// where possible so repeat lookups don't hit the geocoding API again. It needs
+// the service's OpenWeather API key. resolveLocation uses it only for
+// providers that neither take place names directly (wttr.in) nor implement
+// provider.Geocoder.
+func (s *Service) geocode(client *gomatrix.Client, args []string) (coord, error) {
 	country := s.DefaultCountry
 	if country == "" {
 		country = "us"
 	}
 
 	argStr := strings.Join(args, " ")
-	if strings.Index(argStr, ", ") == -1 {
+	key := locationKey(country, argStr)
+
+	geocodeCache.RLock()
+	c, ok := geocodeCache.m[key]
+	geocodeCache.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	if !strings.Contains(argStr, ",") {
 		argStr += ", " + country
 	}
 
+	u, err := url.Parse(geocodeAPIBase)
+	if err != nil {
+		return coord{}, fmt.Errorf("could not parse geocode url: %w", err)
+	}
+
 	q := u.Query()
 	q.Add("q", argStr)
+	q.Add("limit", "1")
 	q.Add("appid", s.APIKey)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	var results []geoResult
+	if err := s.getJSON(client, u.String(), &results); err != nil {
+		return coord{}, err
+	}
+
+	if len(results) == 0 {
+		return coord{}, fmt.Errorf("no location found for %q", argStr)
+	}
+
+	c = coord{Lat: results[0].Lat, Lon: results[0].Lon}
+
+	geocodeCache.Lock()
+	geocodeCache.m[key] = c
+	geocodeCache.Unlock()
+
+	return c, nil
+}
+
+// argsOrDefaultLocation returns args unchanged if non-empty, otherwise falls
+// back to userID's saved home location (see "!weather set home"), split on
+// whitespace to match the shape of a typed location. Returns an empty slice,
+// with no error, if args is empty and userID has no home location set.
+func (s *Service) argsOrDefaultLocation(userID string, args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+
+	home, err := database.GetServiceDB().GetWeatherUserHome(s.ServiceID(), userID)
 	if err != nil {
-		return nil, fmt.Errorf("could not create http request: %w", err)
+		return nil, fmt.Errorf("could not load default location: %w", err)
+	}
+	if home == "" {
+		return nil, nil
+	}
+
+	return strings.Fields(home), nil
+}
+
+// resolveLocation turns the raw command args into a provider.Location.
+// wttr.in accepts a place name directly, so geocoding is skipped for it.
+// Providers that can geocode without an OpenWeather API key (provider.
+// Geocoder) use their own geocoding; everything else falls back to
+// OpenWeather, which requires one.
+func (s *Service) resolveLocation(client *gomatrix.Client, args []string) (provider.Location, error) {
+	name := strings.Join(args, " ")
+
+	if s.providerName() == "wttrin" {
+		return provider.Location{Name: name}, nil
+	}
+
+	p, err := s.weatherProvider()
+	if err != nil {
+		return provider.Location{}, err
+	}
+
+	if g, ok := p.(provider.Geocoder); ok {
+		return g.Geocode(context.Background(), name)
+	}
+
+	c, err := s.geocode(client, args)
+	if err != nil {
+		return provider.Location{}, err
+	}
+
+	return provider.Location{Name: name, Lat: c.Lat, Lon: c.Lon}, nil
+}
+
+// getJSON performs a GET request against url and decodes the JSON response
+// body into out, returning an error describing the body on non-200 responses.
+func (s *Service) getJSON(client *gomatrix.Client, url string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("could not create http request: %w", err)
 	}
 
 	resp, err := client.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making weather request: %w", err)
+		return fmt.Errorf("error making weather request: %w", err)
 	}
 
 	defer resp.Body.Close()
@@ -272,32 +348,204 @@ func (s *Service) search(client *gomatrix.Client, roomID, userID string, args []
 	if resp.StatusCode != http.StatusOK {
 		b, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		return nil, fmt.Errorf("invalid response: %s", string(b))
+		return fmt.Errorf("invalid response: %s", string(b))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid weather response: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) search(client *gomatrix.Client, roomID, userID string, args []string) (interface{}, error) {
+	args, err := s.argsOrDefaultLocation(userID, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 1 {
+		return usageMessage(), nil
+	}
+
+	loc, err := s.resolveLocation(client, args)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := s.effectiveUnit(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	lang, err := s.effectiveLang(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.weatherProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := p.Current(context.Background(), loc, provider.Options{Units: unit, Lang: lang})
+	if err != nil {
+		return nil, err
+	}
+
+	glyph := glyphForCode(conditions.Code)
+
+	body := fmt.Sprintf(
+		"%s %s || Updated: %s || Conditions: %s || Temperature: %s || Humidity: %.0f%% || %s",
+		glyph,
+		strings.Join(args, " "),
+		humanize.Time(conditions.Updated),
+		conditions.Description,
+		formatTemp(conditions.Temp, unit),
+		conditions.Humidity,
+		formatWind(conditions.WindSpeed, conditions.WindDeg, unit),
+	)
+
+	img := iconImgTag(conditions.Icon)
+	if img == "" {
+		return &gomatrix.TextMessage{MsgType: "m.text", Body: body}, nil
+	}
+
+	return gomatrix.HTMLMessage{
+		MsgType:       "m.text",
+		Body:          body,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: fmt.Sprintf("%s %s", img, body),
+	}, nil
+}
+
+func (s *Service) forecast(client *gomatrix.Client, roomID, userID string, args []string) (interface{}, error) {
+	args, err := s.argsOrDefaultLocation(userID, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 1 {
+		return usageMessage(), nil
+	}
+
+	loc, err := s.resolveLocation(client, args)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := s.effectiveUnit(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	lang, err := s.effectiveLang(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.weatherProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := p.Forecast(context.Background(), loc, numDailyForecastDays, provider.Options{Units: unit, Lang: lang})
+	if err != nil {
+		return nil, err
 	}
 
-	var body weatherResponse
+	var plain strings.Builder
+	var rows strings.Builder
+	plain.WriteString(fmt.Sprintf("Forecast for %s:\n", strings.Join(args, " ")))
 
-	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
-		return nil, fmt.Errorf("invalid weather response: %w", err)
+	if len(days) > 0 {
+		art := asciiArt(days[0].Code)
+		plain.WriteString(art[0] + "\n" + art[1] + "\n" + art[2] + "\n")
 	}
 
+	rows.WriteString("<table><tr><th></th><th>Day</th><th>Conditions</th><th>High/Low</th><th>Precip</th><th>Sunrise/Sunset</th></tr>")
+
+	for _, d := range days {
+		day := d.Date.Format("Mon")
+		glyph := glyphForCode(d.Code)
+		sunriseSunset := fmt.Sprintf("%s / %s", d.Sunrise.Format("15:04"), d.Sunset.Format("15:04"))
+
+		plain.WriteString(fmt.Sprintf(
+			"%s %s: %s, high/low %s, %.0f%% precip, sunrise/sunset %s\n",
+			glyph, day, d.Description, dailyMinMax(d, unit), d.Pop*100, sunriseSunset,
+		))
+
+		rows.WriteString(fmt.Sprintf(
+			"<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%.0f%%</td><td>%s</td></tr>",
+			glyph, day, d.Description, dailyMinMax(d, unit), d.Pop*100, sunriseSunset,
+		))
+	}
+
+	rows.WriteString("</table>")
+
 	return gomatrix.HTMLMessage{
-		MsgType: "m.text",
-		//Format:  "org.matrix.custom.html",
-		//FormattedBody: fmt.Sprintf(`<html><body><img src="data:image/png;base64,%s" width="16" height="16"></img></body></html>`, icon),
-		Body: fmt.Sprintf(
-			"%s || Updated: %s || Conditions: %s || Temperature: %s || High/Low: %s || Humidity: %.0f%% || %s",
-			body.Name,
-			humanize.Time(time.Time(body.Dt)),
-			body.Conditions().SimpleString(),
-			body.Main.Temp,
-			body.Main.MinMax(),
-			body.Main.Humidity,
-			body.Wind,
-		),
+		MsgType:       "m.notice",
+		Body:          plain.String(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: rows.String(),
+	}, nil
+}
+
+func (s *Service) hourly(client *gomatrix.Client, roomID, userID string, args []string) (interface{}, error) {
+	args, err := s.argsOrDefaultLocation(userID, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 1 {
+		return usageMessage(), nil
+	}
+
+	loc, err := s.resolveLocation(client, args)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := s.effectiveUnit(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	lang, err := s.effectiveLang(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.weatherProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	hp, ok := p.(provider.HourlyProvider)
+	if !ok {
+		return &gomatrix.TextMessage{
+			MsgType: "m.notice",
+			Body:    fmt.Sprintf("The %s weather backend doesn't support hourly forecasts", s.providerName()),
+		}, nil
+	}
+
+	hours, err := hp.Hourly(context.Background(), loc, numHourlyForecastHours, provider.Options{Units: unit, Lang: lang})
+	if err != nil {
+		return nil, err
+	}
+
+	var lines strings.Builder
+	lines.WriteString(fmt.Sprintf("Next %d hours for %s:\n", numHourlyForecastHours, strings.Join(args, " ")))
+	for _, h := range hours {
+		lines.WriteString(fmt.Sprintf(
+			"%s: %s, %s, %.0f%% precip\n",
+			h.Time.Format("15:04"), h.Description, formatTemp(h.Temp, unit), h.Pop*100,
+		))
+	}
+
+	return &gomatrix.TextMessage{
+		MsgType: "m.notice",
+		Body:    lines.String(),
 	}, nil
 }
 